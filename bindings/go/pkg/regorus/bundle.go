@@ -0,0 +1,434 @@
+package regorus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// bundleMagic identifies a PackBundle frame; OpenBundle rejects anything
+// that doesn't start with it.
+var bundleMagic = [4]byte{'R', 'E', 'G', 'P'}
+
+// bundleFormatVersion is incremented whenever the frame layout below
+// changes in a way old readers can't cope with.
+const bundleFormatVersion = 1
+
+// Codec compresses/decompresses the binary program payload framed inside
+// a bundle. The codec used to pack a bundle is recorded in its header so
+// OpenBundle picks the right one back up. OpenBundle recognizes only the
+// ids of NoneCodec and GzipCodec and rejects a bundle with any other id.
+type Codec interface {
+	// ID is the single byte persisted in the bundle header.
+	ID() byte
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+type noneCodec struct{}
+
+func (noneCodec) ID() byte {
+	return 0
+}
+
+func (noneCodec) Compress(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (noneCodec) Decompress(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte {
+	return 1
+}
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// NoneCodec stores the payload uncompressed.
+var NoneCodec Codec = noneCodec{}
+
+// GzipCodec compresses the payload with gzip. This is the codec PackBundle
+// uses; PackBundle takes no codec argument. No zstd codec is included.
+var GzipCodec Codec = gzipCodec{}
+
+func codecByID(id byte) (Codec, error) {
+	switch id {
+	case NoneCodec.ID():
+		return NoneCodec, nil
+	case GzipCodec.ID():
+		return GzipCodec, nil
+	default:
+		return nil, fmt.Errorf("unknown bundle codec id %d", id)
+	}
+}
+
+// BundleManifest describes a packed program: what it was compiled from
+// and what's required to run it, so a host can validate compatibility
+// before deserializing the payload.
+type BundleManifest struct {
+	Entrypoints      []string          `json:"entrypoints"`
+	ModuleHashes     map[string]string `json:"module_hashes"` // module id -> hex sha256 of its source
+	RegoVersion      string            `json:"rego_version"`
+	RequiredBuiltins []string          `json:"required_builtins,omitempty"`
+	Partial          bool              `json:"partial"`
+	CreatedAt        time.Time         `json:"created_at"`
+	Annotations      map[string]string `json:"annotations,omitempty"`
+
+	// ContentHash is the hex sha256 of the uncompressed program binary.
+	// Set by PackBundle; OpenBundle recomputes it and rejects a mismatch.
+	ContentHash string `json:"content_hash"`
+}
+
+// Signer produces a detached signature over a bundle's manifest+payload
+// hash. KeyID identifies which key signed, mirroring sigstore/cosign's
+// detached-signature model so Verifier implementations can look up the
+// matching public key.
+type Signer interface {
+	KeyID() string
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier authorizes a signature produced by a Signer. Implementations
+// typically look keyID up in a trust store before checking the signature.
+type Verifier interface {
+	Verify(keyID string, data, signature []byte) error
+}
+
+// Ed25519Signer implements Signer with an Ed25519 private key.
+type Ed25519Signer struct {
+	keyID string
+	key   ed25519.PrivateKey
+}
+
+// NewEd25519Signer wraps key under the given key ID.
+func NewEd25519Signer(keyID string, key ed25519.PrivateKey) *Ed25519Signer {
+	return &Ed25519Signer{keyID: keyID, key: key}
+}
+
+func (s *Ed25519Signer) KeyID() string { return s.keyID }
+
+func (s *Ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, data), nil
+}
+
+// Ed25519Verifier authorizes signatures against a fixed set of trusted
+// public keys, keyed by the signer's KeyID.
+type Ed25519Verifier struct {
+	trusted map[string]ed25519.PublicKey
+}
+
+// NewEd25519Verifier builds a Verifier that trusts exactly the given keys.
+func NewEd25519Verifier(trusted map[string]ed25519.PublicKey) *Ed25519Verifier {
+	return &Ed25519Verifier{trusted: trusted}
+}
+
+func (v *Ed25519Verifier) Verify(keyID string, data, signature []byte) error {
+	key, ok := v.trusted[keyID]
+	if !ok {
+		return fmt.Errorf("unauthorized bundle signer %q", keyID)
+	}
+	if !ed25519.Verify(key, data, signature) {
+		return fmt.Errorf("signature verification failed for signer %q", keyID)
+	}
+	return nil
+}
+
+// defaultCodec is the codec PackBundle uses for every bundle. A bundle
+// framed with a codec other than NoneCodec or GzipCodec is rejected by
+// OpenBundle (see codecByID).
+var defaultCodec = GzipCodec
+
+// PackBundle frames program's serialized binary in a versioned,
+// content-addressed container: a header (magic, format version, codec),
+// meta as JSON, the compressed binary payload, and an optional detached
+// signature over the manifest+payload. Pass a nil signer to produce an
+// unsigned bundle.
+func PackBundle(program *Program, meta BundleManifest, signer Signer) ([]byte, error) {
+	codec := defaultCodec
+
+	raw, err := program.SerializeBinary()
+	if err != nil {
+		return nil, fmt.Errorf("serialize program: %w", err)
+	}
+
+	sum := sha256.Sum256(raw)
+	meta.ContentHash = hex.EncodeToString(sum[:])
+
+	manifestJson, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	payload, err := codec.Compress(raw)
+	if err != nil {
+		return nil, fmt.Errorf("compress program: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(bundleMagic[:])
+	buf.WriteByte(bundleFormatVersion)
+	buf.WriteByte(codec.ID())
+	writeFrame(&buf, manifestJson)
+	writeFrame(&buf, payload)
+
+	if signer != nil {
+		signed := signedPayload(manifestJson, payload)
+		sig, err := signer.Sign(signed)
+		if err != nil {
+			return nil, fmt.Errorf("sign bundle: %w", err)
+		}
+		writeFrame(&buf, []byte(signer.KeyID()))
+		writeFrame(&buf, sig)
+	} else {
+		writeFrame(&buf, nil)
+		writeFrame(&buf, nil)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// OpenBundle parses a bundle produced by PackBundle, rejects a content
+// hash mismatch, and - if verifier is non-nil and the bundle carries a
+// signature - rejects an unauthorized or invalid signature. Pass a nil
+// verifier to accept unsigned bundles or skip verification of signed ones.
+func OpenBundle(data []byte, verifier Verifier) (*Program, BundleManifest, error) {
+	raw, meta, err := verifiedBundlePayload(data, verifier)
+	if err != nil {
+		return nil, BundleManifest{}, err
+	}
+
+	program, isPartial, err := DeserializeProgram(raw)
+	if err != nil {
+		return nil, BundleManifest{}, fmt.Errorf("deserialize program: %w", err)
+	}
+	meta.Partial = isPartial
+
+	return program, meta, nil
+}
+
+// parseBundleFrame splits data into its header-identified codec and its
+// four length-prefixed frames (manifest, payload, signer key ID,
+// signature), without decompressing or verifying anything yet.
+func parseBundleFrame(data []byte) (codec Codec, manifestJson, payload, keyId, signature []byte, err error) {
+	r := bytes.NewReader(data)
+
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || magic != bundleMagic {
+		return nil, nil, nil, nil, nil, fmt.Errorf("not a regorus bundle")
+	}
+	var version, codecId byte
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("read bundle version: %w", err)
+	}
+	if version != bundleFormatVersion {
+		return nil, nil, nil, nil, nil, fmt.Errorf("unsupported bundle format version %d", version)
+	}
+	if err := binary.Read(r, binary.BigEndian, &codecId); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("read bundle codec: %w", err)
+	}
+	codec, err = codecByID(codecId)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	manifestJson, err = readFrame(r)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("read bundle manifest: %w", err)
+	}
+	payload, err = readFrame(r)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("read bundle payload: %w", err)
+	}
+	keyId, err = readFrame(r)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("read bundle signer: %w", err)
+	}
+	signature, err = readFrame(r)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("read bundle signature: %w", err)
+	}
+	return codec, manifestJson, payload, keyId, signature, nil
+}
+
+// verifiedBundlePayload parses data, checks its signature against
+// verifier (if both are present) and its payload against the manifest's
+// content hash, and returns the decompressed program bytes plus the
+// manifest. Every caller that hands a Program back to a caller - cached
+// or not - must go through this, so a forged manifest claiming a stale
+// content hash is never enough to pass off different bytes as an
+// already-verified bundle.
+func verifiedBundlePayload(data []byte, verifier Verifier) ([]byte, BundleManifest, error) {
+	codec, manifestJson, payload, keyId, signature, err := parseBundleFrame(data)
+	if err != nil {
+		return nil, BundleManifest{}, err
+	}
+
+	if verifier != nil && len(signature) > 0 {
+		if err := verifier.Verify(string(keyId), signedPayload(manifestJson, payload), signature); err != nil {
+			return nil, BundleManifest{}, fmt.Errorf("verify bundle signature: %w", err)
+		}
+	}
+
+	var meta BundleManifest
+	if err := json.Unmarshal(manifestJson, &meta); err != nil {
+		return nil, BundleManifest{}, fmt.Errorf("parse bundle manifest: %w", err)
+	}
+
+	raw, err := codec.Decompress(payload)
+	if err != nil {
+		return nil, BundleManifest{}, fmt.Errorf("decompress program: %w", err)
+	}
+
+	sum := sha256.Sum256(raw)
+	if hex.EncodeToString(sum[:]) != meta.ContentHash {
+		return nil, BundleManifest{}, fmt.Errorf("bundle content hash mismatch")
+	}
+
+	return raw, meta, nil
+}
+
+func signedPayload(manifestJson, payload []byte) []byte {
+	sum := sha256.Sum256(payload)
+	signed := make([]byte, 0, len(manifestJson)+len(sum))
+	signed = append(signed, manifestJson...)
+	signed = append(signed, sum[:]...)
+	return signed
+}
+
+func writeFrame(buf *bytes.Buffer, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+	buf.Write(data)
+}
+
+func readFrame(r *bytes.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// BundleCache is a content-addressed cache of deserialized programs,
+// keyed by the manifest's SHA-256 content hash. Sidecars that re-pull and
+// re-open the same bundle on every request can skip the deserialize cost
+// on a cache hit.
+type BundleCache struct {
+	mu       sync.Mutex
+	programs map[string]*Program
+}
+
+// NewBundleCache creates an empty cache.
+func NewBundleCache() *BundleCache {
+	return &BundleCache{programs: make(map[string]*Program)}
+}
+
+// CachedProgram is a non-owning view of a Program held by a BundleCache.
+// The cache, not the caller, owns the underlying native handle - since
+// the same CachedProgram.Program may be handed out to many callers for
+// one content hash, Close is a no-op. Release the native handle with
+// BundleCache.Evict or BundleCache.Close instead.
+type CachedProgram struct {
+	*Program
+}
+
+// Close is a no-op: see CachedProgram.
+func (c *CachedProgram) Close() {}
+
+// OpenBundleCached behaves like OpenBundle - it always verifies data's
+// signature (if verifier is non-nil) and its payload against the
+// manifest's content hash - but returns the already-cached Program for
+// that content hash if one was opened before, skipping only the
+// re-deserialization cost. A cache hit never skips verification: a
+// manifest that merely claims a previously-cached content hash, without
+// payload bytes and a signature that actually verify against it, is
+// rejected exactly as a fresh OpenBundle call would reject it. On a cache
+// hit the returned manifest's Partial is the value stored in the manifest,
+// not one re-read from the program as OpenBundle and a cache miss do. The
+// returned CachedProgram is owned by c, not the caller - its Close is a
+// no-op; use BundleCache.Evict or BundleCache.Close to actually release
+// it.
+func (c *BundleCache) OpenBundleCached(data []byte, verifier Verifier) (*CachedProgram, BundleManifest, error) {
+	raw, meta, err := verifiedBundlePayload(data, verifier)
+	if err != nil {
+		return nil, BundleManifest{}, err
+	}
+
+	c.mu.Lock()
+	program, ok := c.programs[meta.ContentHash]
+	c.mu.Unlock()
+	if ok {
+		return &CachedProgram{program}, meta, nil
+	}
+
+	program, isPartial, err := DeserializeProgram(raw)
+	if err != nil {
+		return nil, BundleManifest{}, fmt.Errorf("deserialize program: %w", err)
+	}
+	meta.Partial = isPartial
+
+	c.mu.Lock()
+	c.programs[meta.ContentHash] = program
+	c.mu.Unlock()
+	return &CachedProgram{program}, meta, nil
+}
+
+// Evict closes and removes the cached Program for hash, if any. Any
+// CachedProgram views already handed out for hash become invalid.
+func (c *BundleCache) Evict(hash string) {
+	c.mu.Lock()
+	program, ok := c.programs[hash]
+	delete(c.programs, hash)
+	c.mu.Unlock()
+	if ok {
+		program.Close()
+	}
+}
+
+// Close closes every Program currently held by the cache.
+func (c *BundleCache) Close() {
+	c.mu.Lock()
+	programs := c.programs
+	c.programs = make(map[string]*Program)
+	c.mu.Unlock()
+	for _, program := range programs {
+		program.Close()
+	}
+}
+
@@ -0,0 +1,174 @@
+package regorus
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPackAndOpenBundleRoundTrip(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+	entryPoints := []string{"data.demo.allow"}
+	program, err := CompileProgramFromModules("{}", modules, entryPoints)
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer := NewEd25519Signer("test-key", priv)
+	verifier := NewEd25519Verifier(map[string]ed25519.PublicKey{"test-key": pub})
+
+	meta := BundleManifest{
+		Entrypoints:  entryPoints,
+		ModuleHashes: map[string]string{"demo.rego": "sha256:placeholder"},
+		RegoVersion:  "v1",
+		CreatedAt:    time.Unix(0, 0).UTC(),
+	}
+
+	data, err := PackBundle(program, meta, signer)
+	if err != nil {
+		t.Fatalf("pack bundle: %v", err)
+	}
+
+	opened, openedMeta, err := OpenBundle(data, verifier)
+	if err != nil {
+		t.Fatalf("open bundle: %v", err)
+	}
+	defer opened.Close()
+
+	if openedMeta.RegoVersion != "v1" {
+		t.Fatalf("expected rego version v1, got %s", openedMeta.RegoVersion)
+	}
+	if openedMeta.ContentHash == "" {
+		t.Fatalf("expected content hash to be set")
+	}
+}
+
+func TestOpenBundleRejectsUntrustedSigner(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+	program, err := CompileProgramFromModules("{}", modules, []string{"data.demo.allow"})
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	_, priv, _ := ed25519.GenerateKey(nil)
+	signer := NewEd25519Signer("untrusted-key", priv)
+	verifier := NewEd25519Verifier(map[string]ed25519.PublicKey{})
+
+	data, err := PackBundle(program, BundleManifest{}, signer)
+	if err != nil {
+		t.Fatalf("pack bundle: %v", err)
+	}
+
+	if _, _, err := OpenBundle(data, verifier); err == nil {
+		t.Fatalf("expected signature verification to fail for untrusted signer")
+	}
+}
+
+func TestBundleCacheSkipsReopening(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+	program, err := CompileProgramFromModules("{}", modules, []string{"data.demo.allow"})
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	data, err := PackBundle(program, BundleManifest{}, nil)
+	if err != nil {
+		t.Fatalf("pack bundle: %v", err)
+	}
+
+	cache := NewBundleCache()
+	defer cache.Close()
+	first, _, err := cache.OpenBundleCached(data, nil)
+	if err != nil {
+		t.Fatalf("open bundle: %v", err)
+	}
+	second, _, err := cache.OpenBundleCached(data, nil)
+	if err != nil {
+		t.Fatalf("open bundle (cached): %v", err)
+	}
+	if first.Program != second.Program {
+		t.Fatalf("expected cached OpenBundleCached to return the same Program")
+	}
+
+	// Close on a CachedProgram is a no-op: the cache still owns the
+	// Program, and a third caller must still see it.
+	first.Close()
+	third, _, err := cache.OpenBundleCached(data, nil)
+	if err != nil {
+		t.Fatalf("open bundle (cached after Close): %v", err)
+	}
+	if third.Program != second.Program {
+		t.Fatalf("expected CachedProgram.Close to be a no-op")
+	}
+}
+
+// TestBundleCacheRejectsForgedContentHash guards against a cache hit
+// trusting a manifest's claimed content hash without checking it against
+// the actual bytes submitted: a forged bundle that merely claims a hash
+// already present in the cache, but carries different payload bytes,
+// must be rejected rather than served the unrelated cached Program.
+func TestBundleCacheRejectsForgedContentHash(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+
+	programA, err := CompileProgramFromModules("{}", modules, []string{"data.demo.allow"})
+	if err != nil {
+		t.Fatalf("compile program a: %v", err)
+	}
+	defer programA.Close()
+
+	dataA, err := PackBundle(programA, BundleManifest{}, nil)
+	if err != nil {
+		t.Fatalf("pack bundle a: %v", err)
+	}
+
+	cache := NewBundleCache()
+	defer cache.Close()
+	_, metaA, err := cache.OpenBundleCached(dataA, nil)
+	if err != nil {
+		t.Fatalf("open bundle a: %v", err)
+	}
+
+	modulesB := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy + "\nallow if input.user == \"bob\"\n"}}
+	programB, err := CompileProgramFromModules("{}", modulesB, []string{"data.demo.allow"})
+	if err != nil {
+		t.Fatalf("compile program b: %v", err)
+	}
+	defer programB.Close()
+	rawB, err := programB.SerializeBinary()
+	if err != nil {
+		t.Fatalf("serialize program b: %v", err)
+	}
+
+	// Forge a bundle whose manifest claims program A's already-cached
+	// content hash, but whose payload is actually program B's bytes, and
+	// with no valid signature.
+	forgedManifestJson, err := json.Marshal(BundleManifest{ContentHash: metaA.ContentHash})
+	if err != nil {
+		t.Fatalf("marshal forged manifest: %v", err)
+	}
+	payload, err := GzipCodec.Compress(rawB)
+	if err != nil {
+		t.Fatalf("compress program b: %v", err)
+	}
+	var buf bytes.Buffer
+	buf.Write(bundleMagic[:])
+	buf.WriteByte(bundleFormatVersion)
+	buf.WriteByte(GzipCodec.ID())
+	writeFrame(&buf, forgedManifestJson)
+	writeFrame(&buf, payload)
+	writeFrame(&buf, nil)
+	writeFrame(&buf, nil)
+
+	if _, _, err := cache.OpenBundleCached(buf.Bytes(), nil); err == nil {
+		t.Fatalf("expected forged content hash claim to be rejected, not served from cache")
+	}
+}
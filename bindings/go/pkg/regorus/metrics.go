@@ -0,0 +1,227 @@
+package regorus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// noopTracer is used whenever no tracer has been installed via
+// WithEngineTracer/WithTracer, so span creation is always safe to call
+// without nil checks at every FFI boundary.
+var noopTracer trace.Tracer = noop.NewTracerProvider().Tracer("regorus")
+
+// metricLabelNames is the fixed label schema shared by every metric this
+// package emits. Multi-tenant hosts tag handles with SetLabels using these
+// keys to separate tenants/policy bundles within one shared registry.
+var metricLabelNames = []string{"tenant", "policy_bundle"}
+
+// Metrics bundles the Prometheus collectors for one registry. Share a
+// single Metrics across Engines/Rvms/Programs (Clone preserves it) so
+// they aggregate under the same registration.
+type Metrics struct {
+	PoliciesLoaded   *prometheus.CounterVec
+	QueriesEvaluated *prometheus.CounterVec
+	HostAwaits       *prometheus.CounterVec
+	EvalErrors       *prometheus.CounterVec
+
+	CompileDuration      *prometheus.HistogramVec
+	EvalDuration         *prometheus.HistogramVec
+	ProgramSerializeSize *prometheus.HistogramVec
+
+	LivePrograms *prometheus.GaugeVec
+	LiveRvms     *prometheus.GaugeVec
+
+	// RuleHits, BuiltinInvocations and CoverageBitmapBytes are populated by
+	// Engine.PublishStats from regorus_engine_stats, not updated per-call.
+	RuleHits            *prometheus.GaugeVec
+	BuiltinInvocations  *prometheus.GaugeVec
+	CoverageBitmapBytes *prometheus.GaugeVec
+}
+
+// NewMetrics creates and registers the collector set against reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		PoliciesLoaded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "regorus",
+			Name:      "policies_loaded_total",
+			Help:      "Number of policies successfully added to an Engine.",
+		}, metricLabelNames),
+		QueriesEvaluated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "regorus",
+			Name:      "queries_evaluated_total",
+			Help:      "Number of EvalQuery/EvalRule/Execute/Resume calls completed.",
+		}, metricLabelNames),
+		HostAwaits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "regorus",
+			Name:      "host_await_suspensions_total",
+			Help:      "Number of times execution suspended on __builtin_host_await.",
+		}, metricLabelNames),
+		EvalErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "regorus",
+			Name:      "eval_errors_total",
+			Help:      "Number of AddPolicy and evaluation calls that returned an error.",
+		}, metricLabelNames),
+		CompileDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "regorus",
+			Name:      "compile_duration_seconds",
+			Help:      "Time spent in AddPolicy and CompileProgramFromModules calls.",
+		}, metricLabelNames),
+		EvalDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "regorus",
+			Name:      "eval_duration_seconds",
+			Help:      "Time spent in a single evaluation FFI call.",
+		}, metricLabelNames),
+		ProgramSerializeSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "regorus",
+			Name:      "program_serialize_bytes",
+			Help:      "Size in bytes of a serialized program binary.",
+			Buckets:   prometheus.ExponentialBuckets(1<<10, 4, 8),
+		}, metricLabelNames),
+		LivePrograms: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "regorus",
+			Name:      "live_programs",
+			Help:      "Number of Program handles currently open.",
+		}, metricLabelNames),
+		LiveRvms: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "regorus",
+			Name:      "live_rvms",
+			Help:      "Number of Rvm handles currently open.",
+		}, metricLabelNames),
+		RuleHits: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "regorus",
+			Name:      "interpreter_rule_hits",
+			Help:      "Cumulative rule hits reported by regorus_engine_stats.",
+		}, metricLabelNames),
+		BuiltinInvocations: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "regorus",
+			Name:      "interpreter_builtin_invocations",
+			Help:      "Cumulative builtin invocations reported by regorus_engine_stats.",
+		}, metricLabelNames),
+		CoverageBitmapBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "regorus",
+			Name:      "interpreter_coverage_bitmap_bytes",
+			Help:      "Coverage bitmap size in bytes reported by regorus_engine_stats.",
+		}, metricLabelNames),
+	}
+	for _, c := range []prometheus.Collector{
+		m.PoliciesLoaded, m.QueriesEvaluated, m.HostAwaits, m.EvalErrors,
+		m.CompileDuration, m.EvalDuration, m.ProgramSerializeSize,
+		m.LivePrograms, m.LiveRvms, m.RuleHits, m.BuiltinInvocations, m.CoverageBitmapBytes,
+	} {
+		reg.MustRegister(c)
+	}
+	return m
+}
+
+var (
+	sharedMetricsMu sync.Mutex
+	sharedMetrics   = map[prometheus.Registerer]*Metrics{}
+)
+
+// metricsForRegisterer returns the Metrics already registered against reg,
+// creating and registering one on first use. WithMeter, WithRvmMeter and
+// WithCompileMeter all go through this so pointing multiple Engines/Rvms/
+// compile calls at the same registerer - including prometheus.DefaultRegisterer -
+// aggregates under one set of collectors instead of panicking with
+// "duplicate metrics collector registration attempted" on the second call.
+func metricsForRegisterer(reg prometheus.Registerer) *Metrics {
+	sharedMetricsMu.Lock()
+	defer sharedMetricsMu.Unlock()
+	if m, ok := sharedMetrics[reg]; ok {
+		return m
+	}
+	m := NewMetrics(reg)
+	sharedMetrics[reg] = m
+	return m
+}
+
+// observability is held by pointer in Engine, Rvm and Program (it embeds
+// a sync.RWMutex, so it must never be copied by value). Its zero value
+// is inert: every method below is a safe no-op until WithMeter/WithTracer
+// install a Metrics/Tracer, so instrumentation never changes the FFI
+// contract for callers who don't opt in.
+type observability struct {
+	mu      sync.RWMutex
+	metrics *Metrics
+	labels  []string // values for metricLabelNames, defaults to ""
+	tracer  trace.Tracer
+}
+
+// SetLabels tags subsequent metric observations with the given
+// tenant/policy-bundle identifiers. Unknown keys are ignored; omitted
+// keys from metricLabelNames record as "".
+func (o *observability) SetLabels(labels map[string]string) {
+	values := make([]string, len(metricLabelNames))
+	for i, name := range metricLabelNames {
+		values[i] = labels[name]
+	}
+	o.mu.Lock()
+	o.labels = values
+	o.mu.Unlock()
+}
+
+func (o *observability) labelValues() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.labels == nil {
+		return make([]string, len(metricLabelNames))
+	}
+	return o.labels
+}
+
+func (o *observability) snapshot() (*Metrics, trace.Tracer, []string) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	tracer := o.tracer
+	if tracer == nil {
+		tracer = noopTracer
+	}
+	return o.metrics, tracer, o.labelValues()
+}
+
+// startSpan begins a span for an FFI boundary, falling back to a no-op
+// tracer when none was installed so callers never need a nil check.
+func (o *observability) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	_, tracer, _ := o.snapshot()
+	return tracer.Start(ctx, name)
+}
+
+// endSpan records err (if any) on span and closes it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// observeDuration records d against hist, if a Metrics is installed.
+func (o *observability) observeDuration(hist func(m *Metrics) *prometheus.HistogramVec, d time.Duration) {
+	metrics, _, labels := o.snapshot()
+	if metrics == nil {
+		return
+	}
+	hist(metrics).WithLabelValues(labels...).Observe(d.Seconds())
+}
+
+// incCounter increments counter, if a Metrics is installed.
+func (o *observability) incCounter(counter func(m *Metrics) *prometheus.CounterVec) {
+	metrics, _, labels := o.snapshot()
+	if metrics == nil {
+		return
+	}
+	counter(metrics).WithLabelValues(labels...).Inc()
+}
+
+// addGauge adds delta to gauge, if a Metrics is installed.
+func (o *observability) addGauge(gauge func(m *Metrics) *prometheus.GaugeVec, delta float64) {
+	metrics, _, labels := o.snapshot()
+	if metrics == nil {
+		return
+	}
+	gauge(metrics).WithLabelValues(labels...).Add(delta)
+}
@@ -0,0 +1,49 @@
+package regorus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestObservabilitySetLabelsOrdersByLabelSchema(t *testing.T) {
+	var obs observability
+	obs.SetLabels(map[string]string{"policy_bundle": "bundle-1", "tenant": "acme"})
+
+	values := obs.labelValues()
+	if len(values) != len(metricLabelNames) {
+		t.Fatalf("expected %d label values, got %d", len(metricLabelNames), len(values))
+	}
+	if values[0] != "acme" || values[1] != "bundle-1" {
+		t.Fatalf("unexpected label values: %v", values)
+	}
+}
+
+func TestObservabilityNoopWithoutMeter(t *testing.T) {
+	var obs observability
+	// Must not panic even though no Metrics is installed.
+	obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.EvalErrors })
+	obs.addGauge(func(m *Metrics) *prometheus.GaugeVec { return m.LiveRvms }, 1)
+}
+
+func TestWithMeterRegistersCollectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	e := NewEngine(WithMeter(reg))
+	defer e.Close()
+
+	if e.obs.metrics == nil {
+		t.Fatalf("expected WithMeter to install a Metrics")
+	}
+
+	// Labelled collectors report nothing until a label set is observed,
+	// so record one observation before gathering.
+	e.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.PoliciesLoaded })
+
+	metricFamilies, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	if len(metricFamilies) == 0 {
+		t.Fatalf("expected registered collectors to report at least one metric family")
+	}
+}
@@ -4,17 +4,49 @@ package regorus
 // #include "../../../ffi/regorus.h"
 import "C"
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Engine struct {
-	e *C.RegorusEngine
+	e   *C.RegorusEngine
+	obs *observability
+}
+
+// EngineOption configures optional observability for a new Engine.
+type EngineOption func(*Engine)
+
+// WithMeter installs this Engine's metrics against reg, reusing the
+// Metrics already registered for reg if one exists (see
+// metricsForRegisterer) so pointing an Rvm's WithRvmMeter or a compile
+// call's WithCompileMeter at the same reg aggregates under the same
+// collectors instead of panicking on duplicate registration. Clone
+// copies the reference, so cloned engines report under the same
+// registry.
+func WithMeter(reg prometheus.Registerer) EngineOption {
+	return func(e *Engine) { e.obs.metrics = metricsForRegisterer(reg) }
 }
 
-func NewEngine() *Engine {
+// WithEngineTracer installs an OpenTelemetry tracer that spans every FFI
+// boundary called on this Engine (AddPolicy, EvalQuery, EvalRule).
+func WithEngineTracer(tracer trace.Tracer) EngineOption {
+	return func(e *Engine) { e.obs.tracer = tracer }
+}
+
+func NewEngine(opts ...EngineOption) *Engine {
 	e := new(Engine)
 	e.e = C.regorus_engine_new()
+	e.obs = &observability{}
+	for _, opt := range opts {
+		opt(e)
+	}
 	return e
 }
 
@@ -25,10 +57,21 @@ func (e *Engine) Close() {
 func (e *Engine) Clone() *Engine {
 	c := new(Engine)
 	c.e = C.regorus_engine_clone(e.e)
+	c.obs = e.obs
 	return c
 }
 
+// SetLabels tags this Engine's metrics with tenant/policy-bundle
+// identifiers; see Metrics for the label schema.
+func (e *Engine) SetLabels(labels map[string]string) {
+	e.obs.SetLabels(labels)
+}
+
 func (e *Engine) AddPolicy(path string, rego string) (string, error) {
+	_, span := e.obs.startSpan(context.Background(), "regorus.Engine.AddPolicy")
+	span.SetAttributes(attribute.String("regorus.policy_path", path))
+	start := time.Now()
+
 	path_c := C.CString(path)
 	defer C.free(unsafe.Pointer(path_c))
 
@@ -38,8 +81,14 @@ func (e *Engine) AddPolicy(path string, rego string) (string, error) {
 	result := C.regorus_engine_add_policy(e.e, path_c, rego_c)
 	defer C.regorus_result_drop(result)
 	if result.status != C.RegorusStatusOk {
-		return "", fmt.Errorf("%s", C.GoString(result.error_message))
+		err := fmt.Errorf("%s", C.GoString(result.error_message))
+		e.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.EvalErrors })
+		endSpan(span, err)
+		return "", err
 	}
+	e.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.PoliciesLoaded })
+	e.obs.observeDuration(func(m *Metrics) *prometheus.HistogramVec { return m.CompileDuration }, time.Since(start))
+	endSpan(span, nil)
 	return C.GoString(result.output), nil
 }
 
@@ -123,28 +172,48 @@ func (e *Engine) SetInputFromJsonFile(path string) error {
 }
 
 func (e *Engine) EvalQuery(query string) (string, error) {
+	_, span := e.obs.startSpan(context.Background(), "regorus.Engine.EvalQuery")
+	span.SetAttributes(attribute.String("regorus.query", query))
+	start := time.Now()
+
 	query_c := C.CString(query)
 	defer C.free(unsafe.Pointer(query_c))
 
 	result := C.regorus_engine_eval_query(e.e, query_c)
 	defer C.regorus_result_drop(result)
 	if result.status != C.RegorusStatusOk {
-		return "", fmt.Errorf("%s", C.GoString(result.error_message))
+		err := fmt.Errorf("%s", C.GoString(result.error_message))
+		e.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.EvalErrors })
+		endSpan(span, err)
+		return "", err
 	}
 
+	e.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.QueriesEvaluated })
+	e.obs.observeDuration(func(m *Metrics) *prometheus.HistogramVec { return m.EvalDuration }, time.Since(start))
+	endSpan(span, nil)
 	return C.GoString(result.output), nil
 }
 
 func (e *Engine) EvalRule(rule string) (string, error) {
+	_, span := e.obs.startSpan(context.Background(), "regorus.Engine.EvalRule")
+	span.SetAttributes(attribute.String("regorus.rule", rule))
+	start := time.Now()
+
 	rule_c := C.CString(rule)
 	defer C.free(unsafe.Pointer(rule_c))
 
 	result := C.regorus_engine_eval_rule(e.e, rule_c)
 	defer C.regorus_result_drop(result)
 	if result.status != C.RegorusStatusOk {
-		return "", fmt.Errorf("%s", C.GoString(result.error_message))
+		err := fmt.Errorf("%s", C.GoString(result.error_message))
+		e.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.EvalErrors })
+		endSpan(span, err)
+		return "", err
 	}
 
+	e.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.QueriesEvaluated })
+	e.obs.observeDuration(func(m *Metrics) *prometheus.HistogramVec { return m.EvalDuration }, time.Since(start))
+	endSpan(span, nil)
 	return C.GoString(result.output), nil
 }
 
@@ -204,3 +273,49 @@ func (e *Engine) TakePrints() (string, error) {
 
 	return C.GoString(result.output), nil
 }
+
+// EngineStats holds the cumulative interpreter counters reported by
+// regorus_engine_stats.
+type EngineStats struct {
+	RuleHits            uint64 `json:"rule_hits"`
+	BuiltinInvocations  uint64 `json:"builtin_invocations"`
+	CoverageBitmapBytes uint64 `json:"coverage_bitmap_bytes"`
+}
+
+// Stats fetches the engine's cumulative interpreter counters.
+//
+// NOTE: this calls regorus_engine_stats, which has no implementation in
+// the native regorus_ffi library shipped with this tree yet - the Rust/
+// FFI side of this feature has not landed. This package fails to link
+// against a regorus_ffi build that lacks that symbol, whether or not
+// Stats or PublishStats is called.
+func (e *Engine) Stats() (EngineStats, error) {
+	result := C.regorus_engine_stats(e.e)
+	defer C.regorus_result_drop(result)
+	if result.status != C.RegorusStatusOk {
+		return EngineStats{}, fmt.Errorf("%s", C.GoString(result.error_message))
+	}
+	var stats EngineStats
+	if err := json.Unmarshal([]byte(C.GoString(result.output)), &stats); err != nil {
+		return EngineStats{}, fmt.Errorf("parse engine stats: %w", err)
+	}
+	return stats, nil
+}
+
+// PublishStats fetches Stats and, if a meter is installed via WithMeter,
+// updates its interpreter-internal gauges from the result. Hosts that
+// scrape Prometheus periodically can call this on the same interval to
+// keep rule-hit/builtin-invocation/coverage gauges current.
+func (e *Engine) PublishStats() (EngineStats, error) {
+	stats, err := e.Stats()
+	if err != nil {
+		return stats, err
+	}
+	metrics, _, labels := e.obs.snapshot()
+	if metrics != nil {
+		metrics.RuleHits.WithLabelValues(labels...).Set(float64(stats.RuleHits))
+		metrics.BuiltinInvocations.WithLabelValues(labels...).Set(float64(stats.BuiltinInvocations))
+		metrics.CoverageBitmapBytes.WithLabelValues(labels...).Set(float64(stats.CoverageBitmapBytes))
+	}
+	return stats, nil
+}
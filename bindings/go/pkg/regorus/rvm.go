@@ -4,8 +4,14 @@ package regorus
 // #include "../../../ffi/regorus.h"
 import "C"
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PolicyModule struct {
@@ -14,11 +20,64 @@ type PolicyModule struct {
 }
 
 type Program struct {
-	p *C.RegorusProgram
+	p   *C.RegorusProgram
+	obs *observability
+}
+
+// CompileOption configures optional observability for a compile call.
+type CompileOption func(*observability)
+
+// WithCompileMeter records compile duration and program-size observations
+// against reg for this call, reusing the Metrics already registered for
+// reg if one exists (see metricsForRegisterer) so this can safely share a
+// registerer with WithMeter/WithRvmMeter, and arms the returned Program's
+// live-handle gauge so Close decrements it again.
+func WithCompileMeter(reg prometheus.Registerer) CompileOption {
+	return func(o *observability) { o.metrics = metricsForRegisterer(reg) }
+}
+
+// WithCompileTracer spans the compile call with tracer.
+func WithCompileTracer(tracer trace.Tracer) CompileOption {
+	return func(o *observability) { o.tracer = tracer }
 }
 
 type Rvm struct {
-	vm *C.RegorusRvm
+	vm  *C.RegorusRvm
+	obs *observability
+
+	// deadlineMu guards cancelCh and timer so SetDeadline can race-safely
+	// replace an in-flight deadline from another goroutine.
+	deadlineMu sync.Mutex
+	cancelCh   chan struct{}
+	timer      *time.Timer
+
+	// hostAwaitMu guards hostAwaitHandlers registered via RegisterHostAwait
+	// / RegisterHostAwaitAsync and consulted by Run.
+	hostAwaitMu       sync.Mutex
+	hostAwaitHandlers []*hostAwaitEntry
+}
+
+// RvmOption configures optional observability for a new Rvm.
+type RvmOption func(*Rvm)
+
+// WithTracer installs an OpenTelemetry tracer that spans every FFI
+// boundary called on this Rvm (Execute, Resume).
+func WithTracer(tracer trace.Tracer) RvmOption {
+	return func(r *Rvm) { r.obs.tracer = tracer }
+}
+
+// WithRvmMeter installs this Rvm's metrics against reg, reusing the
+// Metrics already registered for reg if one exists (see
+// metricsForRegisterer). Pass the same registerer used by WithMeter on
+// the owning Engine to aggregate Engine and Rvm metrics together.
+func WithRvmMeter(reg prometheus.Registerer) RvmOption {
+	return func(r *Rvm) { r.obs.metrics = metricsForRegisterer(reg) }
+}
+
+// SetLabels tags this Rvm's metrics with tenant/policy-bundle
+// identifiers; see Metrics for the label schema.
+func (r *Rvm) SetLabels(labels map[string]string) {
+	r.obs.SetLabels(labels)
 }
 
 type Buffer struct {
@@ -41,6 +100,7 @@ func (b *Buffer) Bytes() []byte {
 
 func (p *Program) Close() {
 	if p != nil && p.p != nil {
+		p.obs.addGauge(func(m *Metrics) *prometheus.GaugeVec { return m.LivePrograms }, -1)
 		C.regorus_program_drop(p.p)
 		p.p = nil
 	}
@@ -54,7 +114,12 @@ func (p *Program) SerializeBinary() ([]byte, error) {
 	}
 	buffer := &Buffer{b: (*C.RegorusBuffer)(result.pointer_value)}
 	defer buffer.Close()
-	return buffer.Bytes(), nil
+	bytes := buffer.Bytes()
+	metrics, _, labels := p.obs.snapshot()
+	if metrics != nil {
+		metrics.ProgramSerializeSize.WithLabelValues(labels...).Observe(float64(len(bytes)))
+	}
+	return bytes, nil
 }
 
 func (p *Program) GenerateListing() (string, error) {
@@ -85,10 +150,17 @@ func DeserializeProgram(data []byte) (*Program, bool, error) {
 	if result.status != C.Ok {
 		return nil, false, fmt.Errorf("%s", C.GoString(result.error_message))
 	}
-	return &Program{p: (*C.RegorusProgram)(result.pointer_value)}, bool(isPartial), nil
+	return &Program{p: (*C.RegorusProgram)(result.pointer_value), obs: &observability{}}, bool(isPartial), nil
 }
 
-func CompileProgramFromModules(data string, modules []PolicyModule, entryPoints []string) (*Program, error) {
+func CompileProgramFromModules(data string, modules []PolicyModule, entryPoints []string, opts ...CompileOption) (*Program, error) {
+	obs := &observability{}
+	for _, opt := range opts {
+		opt(obs)
+	}
+	_, span := obs.startSpan(context.Background(), "regorus.CompileProgramFromModules")
+	start := time.Now()
+
 	dataC := C.CString(data)
 	defer C.free(unsafe.Pointer(dataC))
 
@@ -142,9 +214,14 @@ func CompileProgramFromModules(data string, modules []PolicyModule, entryPoints
 	)
 	defer C.regorus_result_drop(result)
 	if result.status != C.Ok {
-		return nil, fmt.Errorf("%s", C.GoString(result.error_message))
+		err := fmt.Errorf("%s", C.GoString(result.error_message))
+		endSpan(span, err)
+		return nil, err
 	}
-	return &Program{p: (*C.RegorusProgram)(result.pointer_value)}, nil
+	obs.observeDuration(func(m *Metrics) *prometheus.HistogramVec { return m.CompileDuration }, time.Since(start))
+	obs.addGauge(func(m *Metrics) *prometheus.GaugeVec { return m.LivePrograms }, 1)
+	endSpan(span, nil)
+	return &Program{p: (*C.RegorusProgram)(result.pointer_value), obs: obs}, nil
 }
 
 func CompileProgramFromEngine(engine *Engine, entryPoints []string) (*Program, error) {
@@ -172,19 +249,31 @@ func CompileProgramFromEngine(engine *Engine, entryPoints []string) (*Program, e
 	if result.status != C.Ok {
 		return nil, fmt.Errorf("%s", C.GoString(result.error_message))
 	}
-	return &Program{p: (*C.RegorusProgram)(result.pointer_value)}, nil
+	return &Program{p: (*C.RegorusProgram)(result.pointer_value), obs: &observability{}}, nil
 }
 
-func NewRvm() (*Rvm, error) {
+func NewRvm(opts ...RvmOption) (*Rvm, error) {
 	vm := C.regorus_rvm_new()
 	if vm == nil {
 		return nil, fmt.Errorf("failed to create RVM")
 	}
-	return &Rvm{vm: vm}, nil
+	r := &Rvm{vm: vm, obs: &observability{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.obs.addGauge(func(m *Metrics) *prometheus.GaugeVec { return m.LiveRvms }, 1)
+	return r, nil
 }
 
 func (r *Rvm) Close() {
 	if r != nil && r.vm != nil {
+		r.deadlineMu.Lock()
+		if r.timer != nil {
+			r.timer.Stop()
+			r.timer = nil
+		}
+		r.deadlineMu.Unlock()
+		r.obs.addGauge(func(m *Metrics) *prometheus.GaugeVec { return m.LiveRvms }, -1)
 		C.regorus_rvm_drop(r.vm)
 		r.vm = nil
 	}
@@ -231,11 +320,20 @@ func (r *Rvm) SetExecutionMode(mode byte) error {
 }
 
 func (r *Rvm) Execute() (string, error) {
+	_, span := r.obs.startSpan(context.Background(), "regorus.Rvm.Execute")
+	start := time.Now()
+
 	result := C.regorus_rvm_execute(r.vm)
 	defer C.regorus_result_drop(result)
 	if result.status != C.Ok {
-		return "", fmt.Errorf("%s", C.GoString(result.error_message))
+		err := fmt.Errorf("%s", C.GoString(result.error_message))
+		r.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.EvalErrors })
+		endSpan(span, err)
+		return "", err
 	}
+	r.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.QueriesEvaluated })
+	r.obs.observeDuration(func(m *Metrics) *prometheus.HistogramVec { return m.EvalDuration }, time.Since(start))
+	endSpan(span, nil)
 	return C.GoString(result.output), nil
 }
 
@@ -260,6 +358,9 @@ func (r *Rvm) ExecuteEntryPointIndex(index uint64) (string, error) {
 }
 
 func (r *Rvm) Resume(resumeValue string, hasValue bool) (string, error) {
+	_, span := r.obs.startSpan(context.Background(), "regorus.Rvm.Resume")
+	start := time.Now()
+
 	var valueC *C.char
 	if hasValue {
 		valueC = C.CString(resumeValue)
@@ -268,8 +369,14 @@ func (r *Rvm) Resume(resumeValue string, hasValue bool) (string, error) {
 	result := C.regorus_rvm_resume(r.vm, valueC, C.bool(hasValue))
 	defer C.regorus_result_drop(result)
 	if result.status != C.Ok {
-		return "", fmt.Errorf("%s", C.GoString(result.error_message))
+		err := fmt.Errorf("%s", C.GoString(result.error_message))
+		r.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.EvalErrors })
+		endSpan(span, err)
+		return "", err
 	}
+	r.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.QueriesEvaluated })
+	r.obs.observeDuration(func(m *Metrics) *prometheus.HistogramVec { return m.EvalDuration }, time.Since(start))
+	endSpan(span, nil)
 	return C.GoString(result.output), nil
 }
 
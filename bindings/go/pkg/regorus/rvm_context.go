@@ -0,0 +1,141 @@
+package regorus
+
+// #include "../../../ffi/regorus.h"
+import "C"
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetDeadline arms (or disarms) a wall-clock deadline for subsequent
+// ExecuteContext/ResumeContext calls on this Rvm. Passing the zero Time
+// clears any previously set deadline. A deadline that has already passed
+// causes the cancel channel to be closed immediately, so the next
+// ExecuteContext/ResumeContext call observes cancellation right away.
+//
+// Rvm callers must not mutate the cancel channel while an execute is in
+// flight; SetDeadline itself is safe to call concurrently with an
+// in-flight execution and will race-safely replace the timer without
+// leaking the old one.
+//
+// NOTE: a deadline set here only takes effect once it reaches
+// regorus_rvm_cancel via ExecuteContext/ResumeContext, which has no
+// implementation in the native regorus_ffi library shipped with this
+// tree yet - see the cancellation caveat on ExecuteContext.
+func (r *Rvm) SetDeadline(t time.Time) {
+	r.deadlineMu.Lock()
+	defer r.deadlineMu.Unlock()
+
+	if r.timer != nil {
+		if !r.timer.Stop() {
+			// The timer already fired (or is about to); the channel it
+			// closed belongs to the old deadline, so start a fresh one
+			// rather than risk closing an already-closed channel.
+			r.cancelCh = nil
+		}
+		r.timer = nil
+	}
+
+	if t.IsZero() {
+		r.cancelCh = nil
+		return
+	}
+
+	r.cancelCh = make(chan struct{})
+	ch := r.cancelCh
+
+	timeout := time.Until(t)
+	if timeout <= 0 {
+		close(ch)
+		return
+	}
+	r.timer = time.AfterFunc(timeout, func() { close(ch) })
+}
+
+// SetExecutionBudget bounds execution to at most instrs interpreter
+// instructions, reusing the same cancellation path as SetDeadline. A
+// budget of zero clears any previously set budget.
+//
+// NOTE: this calls regorus_rvm_set_execution_budget, which (like
+// regorus_rvm_cancel used by cancel/watchCancel below) has no
+// implementation in the native regorus_ffi library shipped with this
+// tree yet - the Rust/FFI side of context-aware cancellation has not
+// landed. This package fails to link against a regorus_ffi build that
+// lacks those symbols, whether or not SetExecutionBudget or anything that
+// cancels an Rvm (ExecuteContext/ResumeContext/Run on timeout) is called.
+func (r *Rvm) SetExecutionBudget(instrs uint64) error {
+	result := C.regorus_rvm_set_execution_budget(r.vm, C.ulong(instrs))
+	defer C.regorus_result_drop(result)
+	if result.status != C.Ok {
+		return fmt.Errorf("%s", C.GoString(result.error_message))
+	}
+	return nil
+}
+
+// deadlineChan returns the currently armed cancel channel, if any.
+func (r *Rvm) deadlineChan() <-chan struct{} {
+	r.deadlineMu.Lock()
+	defer r.deadlineMu.Unlock()
+	return r.cancelCh
+}
+
+// cancel flips the interpreter's cancel flag at the next safe point. It
+// is the single entry point into the native cancellation path, shared by
+// watchCancel here and by dispatchHostAwait's per-handler timeouts in
+// rvm_host_await.go, so every source of cancellation goes through the
+// same call.
+func (r *Rvm) cancel() {
+	C.regorus_rvm_cancel(r.vm)
+}
+
+// watchCancel spawns the goroutine that selects on ctx.Done() and the
+// deadline channel and flips the interpreter's cancel flag at the next
+// safe point. The returned stop function must be called once the
+// execute/resume call returns to release the goroutine.
+func (r *Rvm) watchCancel(ctx context.Context) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.cancel()
+		case <-r.deadlineChan():
+			r.cancel()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// ExecuteContext is the context-aware equivalent of Execute. Cancelling
+// ctx, or a deadline/budget set via SetDeadline/SetExecutionBudget,
+// interrupts execution: the interpreter loop observes the cancellation
+// flag at its next safe point and Execute returns a Canceled status,
+// surfaced here as an error. A ctx that is already done returns ctx.Err()
+// without executing.
+//
+// NOTE: cancellation bottoms out in regorus_rvm_cancel, which has no
+// implementation in the native regorus_ffi library shipped with this
+// tree yet - the Rust/FFI side of context-aware cancellation has not
+// landed. This package fails to link against a regorus_ffi build that
+// lacks it.
+func (r *Rvm) ExecuteContext(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	stop := r.watchCancel(ctx)
+	defer stop()
+	return r.Execute()
+}
+
+// ResumeContext is the context-aware equivalent of Resume. See the
+// cancellation caveat on ExecuteContext. A ctx that is already done
+// returns ctx.Err() without resuming.
+func (r *Rvm) ResumeContext(ctx context.Context, resumeValue string, hasValue bool) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	stop := r.watchCancel(ctx)
+	defer stop()
+	return r.Resume(resumeValue, hasValue)
+}
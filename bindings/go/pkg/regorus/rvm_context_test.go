@@ -0,0 +1,68 @@
+package regorus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRvmExecuteContextCancelled(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+	entryPoints := []string{"data.demo.allow"}
+	program, err := CompileProgramFromModules("{}", modules, entryPoints)
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	vm, err := NewRvm()
+	if err != nil {
+		t.Fatalf("new vm: %v", err)
+	}
+	defer vm.Close()
+
+	if err := vm.LoadProgram(program); err != nil {
+		t.Fatalf("load program: %v", err)
+	}
+	if err := vm.SetInputJson(rvmRegularInput); err != nil {
+		t.Fatalf("set input: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := vm.ExecuteContext(ctx); err == nil {
+		t.Fatalf("expected execute to observe cancellation, got nil error")
+	}
+}
+
+func TestRvmSetDeadlineInThePast(t *testing.T) {
+	vm, err := NewRvm()
+	if err != nil {
+		t.Fatalf("new vm: %v", err)
+	}
+	defer vm.Close()
+
+	vm.SetDeadline(time.Now().Add(-time.Second))
+
+	select {
+	case <-vm.deadlineChan():
+	default:
+		t.Fatalf("expected deadline channel to be closed already")
+	}
+}
+
+func TestRvmSetDeadlineClear(t *testing.T) {
+	vm, err := NewRvm()
+	if err != nil {
+		t.Fatalf("new vm: %v", err)
+	}
+	defer vm.Close()
+
+	vm.SetDeadline(time.Now().Add(time.Hour))
+	vm.SetDeadline(time.Time{})
+
+	if vm.deadlineChan() != nil {
+		t.Fatalf("expected deadline channel to be cleared")
+	}
+}
@@ -0,0 +1,211 @@
+package regorus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HostAwaitContext carries metadata about a suspended __builtin_host_await
+// call to the handler servicing it.
+type HostAwaitContext struct {
+	Namespace string
+	Key       string
+	// State is the raw JSON execution state as returned by
+	// GetExecutionState, for handlers that need details beyond namespace/key.
+	State string
+}
+
+// Result is the outcome of an asynchronous host-await handler, delivered
+// on the channel returned by an AsyncHostAwaitFunc.
+type Result struct {
+	Value json.RawMessage
+	Err   error
+}
+
+// HostAwaitFunc services a single suspended __builtin_host_await call and
+// returns the JSON value to resume the Rvm with.
+type HostAwaitFunc func(key string, ctx HostAwaitContext) (json.RawMessage, error)
+
+// AsyncHostAwaitFunc is the asynchronous counterpart of HostAwaitFunc: it
+// returns immediately with a channel that is sent the result once ready,
+// letting a shared goroutine pool multiplex outstanding host calls across
+// many Rvm instances.
+type AsyncHostAwaitFunc func(key string, ctx HostAwaitContext) <-chan Result
+
+// HostAwaitOption configures an optional aspect of a registered handler.
+type HostAwaitOption func(*hostAwaitEntry)
+
+// WithHostAwaitTimeout bounds how long the driver will wait for this
+// handler before cancelling the Rvm via the same cancel path as
+// ExecuteContext/ResumeContext.
+//
+// NOTE: that cancel path (Rvm.cancel, regorus_rvm_cancel) has no
+// implementation in the native regorus_ffi library shipped with this
+// tree yet - see the cancellation caveat on ExecuteContext.
+func WithHostAwaitTimeout(d time.Duration) HostAwaitOption {
+	return func(e *hostAwaitEntry) { e.timeout = d }
+}
+
+type hostAwaitEntry struct {
+	// pattern is matched against the suspended call's namespace using
+	// path.Match, so "*" registers a default fallback.
+	pattern string
+	sync    HostAwaitFunc
+	async   AsyncHostAwaitFunc
+	timeout time.Duration
+}
+
+// hostAwaitState mirrors the subset of regorus_rvm_get_execution_state's
+// JSON output this driver needs to dispatch a suspension.
+type hostAwaitState struct {
+	Suspended bool   `json:"suspended"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+func (r *Rvm) registerHostAwait(namespace string, entry *hostAwaitEntry) error {
+	if namespace == "" {
+		return fmt.Errorf("host-await namespace pattern must not be empty")
+	}
+	entry.pattern = namespace
+
+	r.hostAwaitMu.Lock()
+	defer r.hostAwaitMu.Unlock()
+	r.hostAwaitHandlers = append(r.hostAwaitHandlers, entry)
+	return nil
+}
+
+// RegisterHostAwait registers a synchronous handler for suspensions whose
+// namespace matches the given glob pattern. Handlers are tried in
+// registration order; register "*" last to install a default fallback.
+func (r *Rvm) RegisterHostAwait(namespace string, handler HostAwaitFunc, opts ...HostAwaitOption) error {
+	entry := &hostAwaitEntry{sync: handler}
+	for _, opt := range opts {
+		opt(entry)
+	}
+	return r.registerHostAwait(namespace, entry)
+}
+
+// RegisterHostAwaitAsync registers a handler whose result arrives on a
+// channel, so outstanding host calls from many Rvm instances can be
+// multiplexed on a shared goroutine pool instead of one goroutine per call.
+func (r *Rvm) RegisterHostAwaitAsync(namespace string, handler AsyncHostAwaitFunc, opts ...HostAwaitOption) error {
+	entry := &hostAwaitEntry{async: handler}
+	for _, opt := range opts {
+		opt(entry)
+	}
+	return r.registerHostAwait(namespace, entry)
+}
+
+func (r *Rvm) lookupHostAwait(namespace string) *hostAwaitEntry {
+	r.hostAwaitMu.Lock()
+	defer r.hostAwaitMu.Unlock()
+	for _, entry := range r.hostAwaitHandlers {
+		if ok, _ := path.Match(entry.pattern, namespace); ok {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (r *Rvm) dispatchHostAwait(ctx context.Context, s hostAwaitState, rawState string) (json.RawMessage, error) {
+	entry := r.lookupHostAwait(s.Namespace)
+	if entry == nil {
+		return nil, fmt.Errorf("no host-await handler registered for namespace %q", s.Namespace)
+	}
+
+	hctx := HostAwaitContext{Namespace: s.Namespace, Key: s.Key, State: rawState}
+
+	var timeoutCh <-chan time.Time
+	if entry.timeout > 0 {
+		timer := time.NewTimer(entry.timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	if entry.sync != nil {
+		type syncResult struct {
+			value json.RawMessage
+			err   error
+		}
+		done := make(chan syncResult, 1)
+		go func() {
+			value, err := entry.sync(s.Key, hctx)
+			done <- syncResult{value, err}
+		}()
+		select {
+		case res := <-done:
+			return res.value, res.err
+		case <-timeoutCh:
+			r.cancel()
+			return nil, fmt.Errorf("host-await handler for namespace %q timed out after %s", s.Namespace, entry.timeout)
+		case <-ctx.Done():
+			r.cancel()
+			return nil, ctx.Err()
+		}
+	}
+
+	resultCh := entry.async(s.Key, hctx)
+	select {
+	case res := <-resultCh:
+		return res.Value, res.Err
+	case <-timeoutCh:
+		r.cancel()
+		return nil, fmt.Errorf("host-await handler for namespace %q timed out after %s", s.Namespace, entry.timeout)
+	case <-ctx.Done():
+		r.cancel()
+		return nil, ctx.Err()
+	}
+}
+
+// Run drives the Rvm to completion, transparently servicing every
+// __builtin_host_await suspension with the registered handlers. It
+// replaces the manual GetExecutionState/Resume loop callers previously
+// had to hand-roll.
+//
+// NOTE: ctx cancellation and WithHostAwaitTimeout both rely on
+// Rvm.cancel (regorus_rvm_cancel), which has no implementation in the
+// native regorus_ffi library shipped with this tree yet - see the
+// cancellation caveat on ExecuteContext.
+func (r *Rvm) Run(ctx context.Context) (string, error) {
+	output, err := r.ExecuteContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		stateJson, err := r.GetExecutionState()
+		if err != nil {
+			return "", err
+		}
+
+		var state hostAwaitState
+		if err := json.Unmarshal([]byte(stateJson), &state); err != nil {
+			return "", fmt.Errorf("parse execution state: %w", err)
+		}
+		if !state.Suspended {
+			return output, nil
+		}
+		r.obs.incCounter(func(m *Metrics) *prometheus.CounterVec { return m.HostAwaits })
+
+		value, err := r.dispatchHostAwait(ctx, state, stateJson)
+		if err != nil {
+			return "", err
+		}
+
+		payload, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		output, err = r.ResumeContext(ctx, string(payload), true)
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package regorus
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRvmRunServicesHostAwait(t *testing.T) {
+	modules := []PolicyModule{{Id: "host_await.rego", Content: rvmPolicy}}
+	entryPoints := []string{"data.demo.allow"}
+	program, err := CompileProgramFromModules("{}", modules, entryPoints)
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	vm, err := NewRvm()
+	if err != nil {
+		t.Fatalf("new vm: %v", err)
+	}
+	defer vm.Close()
+
+	if err := vm.SetExecutionMode(1); err != nil {
+		t.Fatalf("set execution mode: %v", err)
+	}
+	if err := vm.LoadProgram(program); err != nil {
+		t.Fatalf("load program: %v", err)
+	}
+	if err := vm.SetInputJson(rvmInput); err != nil {
+		t.Fatalf("set input: %v", err)
+	}
+
+	err = vm.RegisterHostAwait("account", func(key string, ctx HostAwaitContext) (json.RawMessage, error) {
+		return json.RawMessage(`{"tier":"gold"}`), nil
+	})
+	if err != nil {
+		t.Fatalf("register host-await handler: %v", err)
+	}
+
+	result, err := vm.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result != "true" {
+		t.Fatalf("expected allow=true, got %s", result)
+	}
+}
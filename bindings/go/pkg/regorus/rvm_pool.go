@@ -0,0 +1,209 @@
+package regorus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PoolResult is one input's outcome from RvmPool.EvalMany.
+type PoolResult struct {
+	Output string
+	Err    error
+}
+
+// PoolStats reports how an RvmPool is being used, so callers can
+// right-size it.
+type PoolStats struct {
+	IdleCount      int
+	HighWaterMark  int
+	CumulativeWait time.Duration
+}
+
+// RvmPool is a fixed-size set of Rvms pre-loaded with the same compiled
+// Program, so concurrent callers can evaluate without paying the
+// LoadProgram cost per goroutine. The zero value is not usable; create
+// one with NewRvmPool.
+type RvmPool struct {
+	size int
+	idle chan *Rvm
+
+	// evalMu is read-locked for the duration of a checked-out Rvm and
+	// write-locked by SetDataJson, so a data update always drains every
+	// in-flight evaluation before touching the pooled Rvms.
+	evalMu sync.RWMutex
+
+	statsMu        sync.Mutex
+	activeCount    int
+	highWaterMark  int
+	cumulativeWait time.Duration
+}
+
+// NewRvmPool pre-loads size Rvms with program and returns a pool ready
+// for concurrent Eval/EvalMany calls.
+func NewRvmPool(program *Program, size int) (*RvmPool, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("pool size must be positive, got %d", size)
+	}
+
+	pool := &RvmPool{size: size, idle: make(chan *Rvm, size)}
+	for i := 0; i < size; i++ {
+		vm, err := NewRvm()
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("create pooled rvm %d: %w", i, err)
+		}
+		if err := vm.LoadProgram(program); err != nil {
+			vm.Close()
+			pool.Close()
+			return nil, fmt.Errorf("load program into pooled rvm %d: %w", i, err)
+		}
+		pool.idle <- vm
+	}
+	return pool, nil
+}
+
+// Close drains and closes every pooled Rvm. It must not be called while
+// an Eval/EvalMany call is in flight.
+func (p *RvmPool) Close() {
+	for {
+		select {
+		case vm := <-p.idle:
+			vm.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (p *RvmPool) checkout(ctx context.Context) (*Rvm, error) {
+	start := time.Now()
+	var vm *Rvm
+	select {
+	case vm = <-p.idle:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.statsMu.Lock()
+	p.cumulativeWait += time.Since(start)
+	p.activeCount++
+	if p.activeCount > p.highWaterMark {
+		p.highWaterMark = p.activeCount
+	}
+	p.statsMu.Unlock()
+
+	return vm, nil
+}
+
+func (p *RvmPool) checkin(vm *Rvm) {
+	p.statsMu.Lock()
+	p.activeCount--
+	p.statsMu.Unlock()
+	p.idle <- vm
+}
+
+// Eval blocks until an idle Rvm is available (or ctx is done), evaluates
+// entryPoint against input on it, and returns the Rvm to the pool.
+func (p *RvmPool) Eval(ctx context.Context, input string, entryPoint string) (string, error) {
+	p.evalMu.RLock()
+	defer p.evalMu.RUnlock()
+
+	vm, err := p.checkout(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer p.checkin(vm)
+
+	if err := vm.SetInputJson(input); err != nil {
+		return "", err
+	}
+	return vm.ExecuteEntryPoint(entryPoint)
+}
+
+// EvalMany fans inputs out across every pooled Rvm, one worker per Rvm,
+// and preserves input order in the returned slice. If ctx is cancelled
+// before all inputs are evaluated, EvalMany returns the partial results
+// alongside ctx.Err(); any input never dispatched to a worker gets
+// ctx.Err() as its own Err so callers inspecting individual results can't
+// mistake "never evaluated" for a genuine empty-string success.
+func (p *RvmPool) EvalMany(ctx context.Context, inputs []string, entryPoint string) ([]PoolResult, error) {
+	results := make([]PoolResult, len(inputs))
+	indices := make(chan int)
+	dispatched := make([]bool, len(inputs))
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.size; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				output, err := p.Eval(ctx, inputs[idx], entryPoint)
+				results[idx] = PoolResult{Output: output, Err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indices)
+		for i := range inputs {
+			select {
+			case indices <- i:
+				dispatched[i] = true
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		for i, ok := range dispatched {
+			if !ok {
+				results[i] = PoolResult{Err: err}
+			}
+		}
+		return results, err
+	}
+	return results, nil
+}
+
+// SetDataJson broadcasts data to every pooled Rvm. It blocks until every
+// in-flight Eval/EvalMany call has returned its Rvm to the pool before
+// updating any of them, so no evaluation ever observes a half-updated
+// pool.
+func (p *RvmPool) SetDataJson(data string) error {
+	p.evalMu.Lock()
+	defer p.evalMu.Unlock()
+
+	vms := make([]*Rvm, 0, p.size)
+	for i := 0; i < p.size; i++ {
+		vms = append(vms, <-p.idle)
+	}
+	defer func() {
+		for _, vm := range vms {
+			p.idle <- vm
+		}
+	}()
+
+	for _, vm := range vms {
+		if err := vm.SetDataJson(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats reports the pool's current idle count, the high-water mark of
+// concurrently checked-out Rvms, and the cumulative time callers have
+// spent waiting for one.
+func (p *RvmPool) Stats() PoolStats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	return PoolStats{
+		IdleCount:      len(p.idle),
+		HighWaterMark:  p.highWaterMark,
+		CumulativeWait: p.cumulativeWait,
+	}
+}
@@ -0,0 +1,126 @@
+package regorus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRvmPoolEvalMany(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+	program, err := CompileProgramFromModules("{}", modules, []string{"data.demo.allow"})
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	pool, err := NewRvmPool(program, 3)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	defer pool.Close()
+
+	inputs := []string{rvmRegularInput, `{"user":"bob","active":true}`, rvmRegularInput}
+	results, err := pool.EvalMany(context.Background(), inputs, "data.demo.allow")
+	if err != nil {
+		t.Fatalf("eval many: %v", err)
+	}
+	if len(results) != len(inputs) {
+		t.Fatalf("expected %d results, got %d", len(inputs), len(results))
+	}
+	if results[0].Output != "true" || results[2].Output != "true" {
+		t.Fatalf("expected alice inputs to allow, got %+v", results)
+	}
+	if results[1].Output != "false" {
+		t.Fatalf("expected bob input to be denied, got %+v", results)
+	}
+
+	stats := pool.Stats()
+	if stats.IdleCount != 3 {
+		t.Fatalf("expected all 3 rvms idle after EvalMany, got %d", stats.IdleCount)
+	}
+}
+
+func TestRvmPoolEvalManyMarksSkippedInputsOnCancel(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+	program, err := CompileProgramFromModules("{}", modules, []string{"data.demo.allow"})
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	pool, err := NewRvmPool(program, 1)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	inputs := []string{rvmRegularInput, rvmRegularInput, rvmRegularInput}
+	results, err := pool.EvalMany(ctx, inputs, "data.demo.allow")
+	if err == nil {
+		t.Fatalf("expected EvalMany to return ctx.Err()")
+	}
+	for i, res := range results {
+		// A never-dispatched input must not be left as the zero
+		// PoolResult{Output:"", Err:nil}, which is indistinguishable
+		// from a genuine empty-string success.
+		if res.Output == "" && res.Err == nil {
+			t.Fatalf("results[%d] is the ambiguous zero value; expected ctx.Err() on a skipped input", i)
+		}
+	}
+}
+
+func TestRvmPoolSetDataJsonDrainsInFlight(t *testing.T) {
+	modules := []PolicyModule{{Id: "demo.rego", Content: rvmRegularPolicy}}
+	program, err := CompileProgramFromModules("{}", modules, []string{"data.demo.allow"})
+	if err != nil {
+		t.Fatalf("compile program: %v", err)
+	}
+	defer program.Close()
+
+	pool, err := NewRvmPool(program, 2)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	defer pool.Close()
+
+	// Simulate an in-flight Eval by checking out a pooled Rvm under
+	// evalMu's read lock, exactly as Eval does, and holding it until the
+	// test releases it.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	evalDone := make(chan struct{})
+	go func() {
+		pool.evalMu.RLock()
+		defer pool.evalMu.RUnlock()
+		vm := <-pool.idle
+		close(started)
+		<-release
+		pool.idle <- vm
+		close(evalDone)
+	}()
+	<-started
+
+	setDataDone := make(chan error, 1)
+	go func() { setDataDone <- pool.SetDataJson("{}") }()
+
+	select {
+	case <-setDataDone:
+		t.Fatalf("SetDataJson returned before the in-flight eval released its Rvm")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-evalDone
+	if err := <-setDataDone; err != nil {
+		t.Fatalf("set data: %v", err)
+	}
+
+	stats := pool.Stats()
+	if stats.IdleCount != 2 {
+		t.Fatalf("expected all rvms idle after SetDataJson, got %d", stats.IdleCount)
+	}
+}